@@ -0,0 +1,234 @@
+// Package extension holds goldmark extensions that are not part of the
+// core AST or renderer: each one is opt-in, bundling whatever parser and/or
+// renderer it needs, and is only wired in when a caller asks for it via
+// goldmark.WithExtensions.
+package extension
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	gast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// defaultFootnoteReturnLinkContent is the text used for the return-to-text
+// link at the end of each footnote, unless overridden with
+// WithFootnoteReturnLinkContent.
+const defaultFootnoteReturnLinkContent = "↩"
+
+// A FootnoteOption interface configures the footnote HTML renderer.
+type FootnoteOption interface {
+	SetFootnoteOption(*footnoteConfig)
+}
+
+type footnoteConfig struct {
+	html.Config
+	FootnoteReturnLinks       bool
+	FootnoteReturnLinkContent string
+	FootnoteNoHRTag           bool
+}
+
+func newFootnoteConfig() footnoteConfig {
+	return footnoteConfig{
+		Config:                    html.NewConfig(),
+		FootnoteReturnLinks:       true,
+		FootnoteReturnLinkContent: defaultFootnoteReturnLinkContent,
+		FootnoteNoHRTag:           false,
+	}
+}
+
+// SetOption implements renderer.NodeRenderer.SetOption.
+func (c *footnoteConfig) SetOption(name renderer.OptionName, value interface{}) {
+	switch name {
+	case optFootnoteReturnLinks:
+		c.FootnoteReturnLinks = value.(bool)
+	case optFootnoteReturnLinkContent:
+		c.FootnoteReturnLinkContent = value.(string)
+	case optFootnoteNoHRTag:
+		c.FootnoteNoHRTag = value.(bool)
+	default:
+		c.Config.SetOption(name, value)
+	}
+}
+
+// optFootnoteReturnLinks is an option name used in WithFootnoteReturnLinks.
+const optFootnoteReturnLinks renderer.OptionName = "FootnoteReturnLinks"
+
+type withFootnoteReturnLinks struct {
+	value bool
+}
+
+func (o *withFootnoteReturnLinks) SetConfig(c *renderer.Config) {
+	c.Options[optFootnoteReturnLinks] = o.value
+}
+
+func (o *withFootnoteReturnLinks) SetFootnoteOption(c *footnoteConfig) {
+	c.FootnoteReturnLinks = o.value
+}
+
+// WithFootnoteReturnLinks is a functional option that toggles the
+// return-to-text link appended to each rendered footnote.
+func WithFootnoteReturnLinks(enabled bool) interface {
+	renderer.Option
+	FootnoteOption
+} {
+	return &withFootnoteReturnLinks{enabled}
+}
+
+// optFootnoteReturnLinkContent is an option name used in
+// WithFootnoteReturnLinkContent.
+const optFootnoteReturnLinkContent renderer.OptionName = "FootnoteReturnLinkContent"
+
+type withFootnoteReturnLinkContent struct {
+	value string
+}
+
+func (o *withFootnoteReturnLinkContent) SetConfig(c *renderer.Config) {
+	c.Options[optFootnoteReturnLinkContent] = o.value
+}
+
+func (o *withFootnoteReturnLinkContent) SetFootnoteOption(c *footnoteConfig) {
+	c.FootnoteReturnLinkContent = o.value
+}
+
+// WithFootnoteReturnLinkContent is a functional option that overrides the
+// text of the return-to-text link (the default is "↩").
+func WithFootnoteReturnLinkContent(content string) interface {
+	renderer.Option
+	FootnoteOption
+} {
+	return &withFootnoteReturnLinkContent{content}
+}
+
+// optFootnoteNoHRTag is an option name used in WithFootnoteNoHRTag.
+const optFootnoteNoHRTag renderer.OptionName = "FootnoteNoHRTag"
+
+type withFootnoteNoHRTag struct {
+	value bool
+}
+
+func (o *withFootnoteNoHRTag) SetConfig(c *renderer.Config) {
+	c.Options[optFootnoteNoHRTag] = o.value
+}
+
+func (o *withFootnoteNoHRTag) SetFootnoteOption(c *footnoteConfig) {
+	c.FootnoteNoHRTag = o.value
+}
+
+// WithFootnoteNoHRTag is a functional option that suppresses the <hr>
+// separator normally written before the footnote list.
+func WithFootnoteNoHRTag(enabled bool) interface {
+	renderer.Option
+	FootnoteOption
+} {
+	return &withFootnoteNoHRTag{enabled}
+}
+
+// footnoteHTMLRenderer renders the extension/ast footnote node kinds
+// (Footnote, FootnoteList, FootnoteLink, FootnoteBackLink). It is a
+// standalone renderer.NodeRenderer: unlike html.Renderer it is never
+// registered by default, since the core HTML renderer has no notion of
+// footnotes and plain-HTML-only callers shouldn't pay for them.
+type footnoteHTMLRenderer struct {
+	footnoteConfig
+}
+
+// NewFootnoteHTMLRenderer returns a new renderer.NodeRenderer for footnotes,
+// configured with the given FootnoteOptions.
+func NewFootnoteHTMLRenderer(opts ...FootnoteOption) renderer.NodeRenderer {
+	r := &footnoteHTMLRenderer{footnoteConfig: newFootnoteConfig()}
+	for _, opt := range opts {
+		opt.SetFootnoteOption(&r.footnoteConfig)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *footnoteHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(gast.KindFootnote, r.renderFootnote)
+	reg.Register(gast.KindFootnoteList, r.renderFootnoteList)
+	reg.Register(gast.KindFootnoteLink, r.renderFootnoteLink)
+	reg.Register(gast.KindFootnoteBackLink, r.renderFootnoteBackLink)
+}
+
+func (r *footnoteHTMLRenderer) renderFootnoteLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*gast.FootnoteLink)
+	fmt.Fprintf(w, `<sup><a id="fnref:%d" href="#fn:%d">%d</a></sup>`, n.Index, n.Index, n.Index)
+	return ast.WalkContinue, nil
+}
+
+func (r *footnoteHTMLRenderer) renderFootnoteBackLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	if !r.FootnoteReturnLinks {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*gast.FootnoteBackLink)
+	content := r.FootnoteReturnLinkContent
+	if content == "" {
+		content = defaultFootnoteReturnLinkContent
+	}
+	fmt.Fprintf(w, ` <a href="#fnref:%d" class="footnote-backref">%s</a>`, n.Index, content)
+	return ast.WalkContinue, nil
+}
+
+func (r *footnoteHTMLRenderer) renderFootnoteList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString("<div class=\"footnotes\">\n")
+		if !r.FootnoteNoHRTag {
+			if r.XHTML {
+				w.WriteString("<hr />\n")
+			} else {
+				w.WriteString("<hr>\n")
+			}
+		}
+		w.WriteString("<ol>\n")
+		return ast.WalkContinue, nil
+	}
+	w.WriteString("</ol>\n</div>\n")
+	return ast.WalkContinue, nil
+}
+
+func (r *footnoteHTMLRenderer) renderFootnote(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*gast.Footnote)
+	if entering {
+		fmt.Fprintf(w, "<li id=\"fn:%d\">\n", n.Index)
+		return ast.WalkContinue, nil
+	}
+	w.WriteString("</li>\n")
+	return ast.WalkContinue, nil
+}
+
+type footnoteExtension struct {
+	options []FootnoteOption
+}
+
+// NewFootnote returns a goldmark.Extender that registers the footnote HTML
+// renderer, configured with the given FootnoteOptions. Use it instead of
+// Footnote when you need to pass options.
+func NewFootnote(opts ...FootnoteOption) goldmark.Extender {
+	return &footnoteExtension{options: opts}
+}
+
+// Extend implements goldmark.Extender.
+func (e *footnoteExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewFootnoteHTMLRenderer(e.options...), 500),
+	))
+}
+
+// Footnote is an extension that renders footnotes (the Footnote,
+// FootnoteList, FootnoteLink and FootnoteBackLink kinds from
+// github.com/yuin/goldmark/extension/ast). It is opt-in: register it with
+// goldmark.WithExtensions(extension.Footnote), the same way as any other
+// goldmark extension, rather than relying on the core HTML renderer, which
+// knows nothing about footnotes.
+var Footnote = NewFootnote()
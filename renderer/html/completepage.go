@@ -0,0 +1,232 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// CompletePageOptions carries the document-level settings used by
+// WithCompletePage to render a full HTML document instead of a fragment.
+type CompletePageOptions struct {
+	Title    string
+	Language string
+	Charset  string
+	Icon     string
+	CSSHrefs []string
+	JSHrefs  []string
+
+	// Header, if set, is written verbatim at the top of <body>, taking
+	// precedence over a TOC installed via WithTOC.
+	Header []byte
+}
+
+// CompletePage is an option name used in WithCompletePage.
+const CompletePage renderer.OptionName = "CompletePage"
+
+type withCompletePage struct {
+	value CompletePageOptions
+}
+
+func (o *withCompletePage) SetConfig(c *renderer.Config) {
+	c.Options[CompletePage] = o.value
+}
+
+func (o *withCompletePage) SetHTMLOption(c *Config) {
+	opts := o.value
+	c.CompletePage = &opts
+}
+
+// WithCompletePage is a functional option that makes the renderer emit a
+// complete HTML document (doctype, <html>, <head>, <body>) instead of an
+// HTML fragment.
+func WithCompletePage(opts CompletePageOptions) interface {
+	renderer.Option
+	Option
+} {
+	return &withCompletePage{opts}
+}
+
+// TOC is an option name used in WithTOC.
+const TOC renderer.OptionName = "TOC"
+
+type withTOC struct{}
+
+func (o *withTOC) SetConfig(c *renderer.Config) {
+	c.Options[TOC] = true
+}
+
+func (o *withTOC) SetHTMLOption(c *Config) {
+	c.TOC = true
+}
+
+// WithTOC is a functional option that makes the renderer assign stable,
+// deduplicated IDs to headings and inject a table of contents at the top
+// of the document (or of <body>, when combined with WithCompletePage).
+func WithTOC() interface {
+	renderer.Option
+	Option
+} {
+	return &withTOC{}
+}
+
+func (r *Renderer) writeCompletePagePrologue(w util.BufWriter, toc []byte) {
+	opts := r.CompletePage
+	lang := opts.Language
+	if lang == "" {
+		lang = "en"
+	}
+	charset := opts.Charset
+	if charset == "" {
+		charset = "utf-8"
+	}
+	w.WriteString("<!DOCTYPE html>\n<html lang=\"")
+	w.Write(util.EscapeHTML([]byte(lang)))
+	w.WriteString("\">\n<head>\n<meta charset=\"")
+	w.Write(util.EscapeHTML([]byte(charset)))
+	w.WriteString("\">\n")
+	if opts.Title != "" {
+		w.WriteString("<title>")
+		w.Write(util.EscapeHTML([]byte(opts.Title)))
+		w.WriteString("</title>\n")
+	}
+	if opts.Icon != "" {
+		w.WriteString(`<link rel="icon" href="`)
+		w.Write(util.EscapeHTML([]byte(opts.Icon)))
+		w.WriteString("\">\n")
+	}
+	for _, href := range opts.CSSHrefs {
+		w.WriteString(`<link rel="stylesheet" href="`)
+		w.Write(util.EscapeHTML([]byte(href)))
+		w.WriteString("\">\n")
+	}
+	for _, href := range opts.JSHrefs {
+		w.WriteString(`<script src="`)
+		w.Write(util.EscapeHTML([]byte(href)))
+		w.WriteString("\"></script>\n")
+	}
+	w.WriteString("</head>\n<body>\n")
+	switch {
+	case opts.Header != nil:
+		w.Write(opts.Header)
+	case toc != nil:
+		w.Write(toc)
+	}
+}
+
+func (r *Renderer) writeCompletePageEpilogue(w util.BufWriter) {
+	w.WriteString("</body>\n</html>\n")
+}
+
+type headingInfo struct {
+	Level int
+	ID    string
+	Text  []byte
+}
+
+// renderTOC walks doc for headings, assigns each one a stable slugified
+// (and, on collision, numerically suffixed) ID via the "id" attribute, and
+// returns a <nav>-wrapped nested <ul> linking to them.
+func (r *Renderer) renderTOC(doc ast.Node, source []byte) []byte {
+	var headings []headingInfo
+	seen := map[string]int{}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		h, ok := n.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		text := h.Text(source)
+		id := slugify(util.BytesToReadOnlyString(text))
+		if id == "" {
+			id = "section"
+		}
+		if count, ok := seen[id]; ok {
+			seen[id] = count + 1
+			id = fmt.Sprintf("%s-%d", id, count+1)
+		} else {
+			seen[id] = 0
+		}
+		h.SetAttribute(attrNameID, []byte(id))
+		headings = append(headings, headingInfo{Level: h.Level, ID: id, Text: text})
+		return ast.WalkContinue, nil
+	})
+	if len(headings) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<nav>\n<ul>\n")
+	base := headings[0].Level
+	depth := 0
+	// open[d] tracks whether the <li> at nesting depth d is still open, so a
+	// child <ul> can be written inside it instead of as its sibling.
+	open := []bool{false}
+	for _, h := range headings {
+		d := h.Level - base
+		if d < 0 {
+			d = 0
+		}
+		for depth < d {
+			buf.WriteString("<ul>\n")
+			depth++
+			open = append(open, false)
+		}
+		for depth > d {
+			if open[depth] {
+				buf.WriteString("</li>\n")
+				open[depth] = false
+			}
+			buf.WriteString("</ul>\n")
+			open = open[:depth]
+			depth--
+		}
+		if open[depth] {
+			buf.WriteString("</li>\n")
+		}
+		fmt.Fprintf(&buf, "<li><a href=\"#%s\">", h.ID)
+		buf.Write(util.EscapeHTML(h.Text))
+		buf.WriteString("</a>")
+		open[depth] = true
+	}
+	for depth > 0 {
+		if open[depth] {
+			buf.WriteString("</li>\n")
+		}
+		buf.WriteString("</ul>\n")
+		open = open[:depth]
+		depth--
+	}
+	if open[0] {
+		buf.WriteString("</li>\n")
+	}
+	buf.WriteString("</ul>\n</nav>\n")
+	return buf.Bytes()
+}
+
+// slugify turns s into a lowercase, hyphen-separated identifier suitable
+// for use as an HTML id, mirroring the scheme blackfriday uses for heading
+// anchors.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case r == ' ' || r == '-' || r == '_' || r == '\t' || r == '\n':
+			if !dash && b.Len() > 0 {
+				b.WriteByte('-')
+				dash = true
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "-")
+}
@@ -3,7 +3,9 @@ package html
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/renderer"
@@ -12,19 +14,35 @@ import (
 
 // A Config struct has configurations for the HTML based renderers.
 type Config struct {
-	Writer    Writer
-	HardWraps bool
-	XHTML     bool
-	Unsafe    bool
+	Writer            Writer
+	HardWraps         bool
+	XHTML             bool
+	Unsafe            bool
+	Safelink          bool
+	NofollowLinks     bool
+	NoreferrerLinks   bool
+	NoopenerLinks     bool
+	HrefTargetBlank   bool
+	AllowedURISchemes []string
+	CompletePage      *CompletePageOptions
+	TOC               bool
+	Sanitizer         Sanitizer
+	URLFilter         URLFilter
 }
 
 // NewConfig returns a new Config with defaults.
 func NewConfig() Config {
 	return Config{
-		Writer:    DefaultWriter,
-		HardWraps: false,
-		XHTML:     false,
-		Unsafe:    false,
+		Writer:            DefaultWriter,
+		HardWraps:         false,
+		XHTML:             false,
+		Unsafe:            false,
+		Safelink:          false,
+		NofollowLinks:     false,
+		NoreferrerLinks:   false,
+		NoopenerLinks:     false,
+		HrefTargetBlank:   false,
+		AllowedURISchemes: append([]string{}, defaultAllowedURISchemes...),
 	}
 }
 
@@ -39,6 +57,27 @@ func (c *Config) SetOption(name renderer.OptionName, value interface{}) {
 		c.Unsafe = value.(bool)
 	case TextWriter:
 		c.Writer = value.(Writer)
+	case Safelink:
+		c.Safelink = value.(bool)
+	case NofollowLinks:
+		c.NofollowLinks = value.(bool)
+	case NoreferrerLinks:
+		c.NoreferrerLinks = value.(bool)
+	case NoopenerLinks:
+		c.NoopenerLinks = value.(bool)
+	case HrefTargetBlank:
+		c.HrefTargetBlank = value.(bool)
+	case AllowedURISchemes:
+		c.AllowedURISchemes = append(c.AllowedURISchemes, value.([]string)...)
+	case CompletePage:
+		opts := value.(CompletePageOptions)
+		c.CompletePage = &opts
+	case TOC:
+		c.TOC = value.(bool)
+	case HTMLSanitizer:
+		c.Sanitizer = value.(Sanitizer)
+	case LinkURLFilter:
+		c.URLFilter = value.(URLFilter)
 	}
 }
 
@@ -140,6 +179,217 @@ func WithUnsafe() interface {
 	return &withUnsafe{}
 }
 
+// A Sanitizer interface sanitizes raw HTML (HTML blocks and inline raw
+// HTML) before it is written to the output. It is the escape hatch for
+// callers who want to allow some raw HTML through without going fully
+// Unsafe.
+type Sanitizer interface {
+	// Sanitize writes a sanitized version of html to dst.
+	Sanitize(dst io.Writer, html []byte) error
+}
+
+// HTMLSanitizer is an option name used in WithSanitizer.
+const HTMLSanitizer renderer.OptionName = "HTMLSanitizer"
+
+type withSanitizer struct {
+	value Sanitizer
+}
+
+func (o *withSanitizer) SetConfig(c *renderer.Config) {
+	c.Options[HTMLSanitizer] = o.value
+}
+
+func (o *withSanitizer) SetHTMLOption(c *Config) {
+	c.Sanitizer = o.value
+}
+
+// WithSanitizer is a functional option that routes raw HTML (otherwise
+// replaced with an "omitted" comment when Unsafe is false) through the
+// given Sanitizer instead.
+func WithSanitizer(sanitizer Sanitizer) interface {
+	renderer.Option
+	Option
+} {
+	return &withSanitizer{sanitizer}
+}
+
+// A URLFilter interface decides whether a link or image destination is
+// dangerous and should be dropped. It lets callers substitute their own
+// allowlist logic for the package-level IsDangerousURL.
+type URLFilter interface {
+	IsDangerousURL(url []byte) bool
+}
+
+// LinkURLFilter is an option name used in WithURLFilter.
+const LinkURLFilter renderer.OptionName = "LinkURLFilter"
+
+type withURLFilter struct {
+	value URLFilter
+}
+
+func (o *withURLFilter) SetConfig(c *renderer.Config) {
+	c.Options[LinkURLFilter] = o.value
+}
+
+func (o *withURLFilter) SetHTMLOption(c *Config) {
+	c.URLFilter = o.value
+}
+
+// WithURLFilter is a functional option that overrides the package-level
+// IsDangerousURL check used by renderLink and renderImage.
+func WithURLFilter(filter URLFilter) interface {
+	renderer.Option
+	Option
+} {
+	return &withURLFilter{filter}
+}
+
+// defaultAllowedURISchemes is the set of link destination schemes that
+// WithSafelink lets through by default.
+var defaultAllowedURISchemes = []string{"http", "https", "mailto", "ftp", "tel"}
+
+// Safelink is an option name used in WithSafelink.
+const Safelink renderer.OptionName = "Safelink"
+
+type withSafelink struct {
+}
+
+func (o *withSafelink) SetConfig(c *renderer.Config) {
+	c.Options[Safelink] = true
+}
+
+func (o *withSafelink) SetHTMLOption(c *Config) {
+	c.Safelink = true
+}
+
+// WithSafelink is a functional option that renders link destinations only
+// when their scheme is in the allowlist (see WithAllowedURISchemes),
+// dropping the href of anything else. Destinations without a scheme
+// (relative paths, fragments) are always rendered.
+func WithSafelink() interface {
+	renderer.Option
+	Option
+} {
+	return &withSafelink{}
+}
+
+// AllowedURISchemes is an option name used in WithAllowedURISchemes.
+const AllowedURISchemes renderer.OptionName = "AllowedURISchemes"
+
+type withAllowedURISchemes struct {
+	value []string
+}
+
+func (o *withAllowedURISchemes) SetConfig(c *renderer.Config) {
+	c.Options[AllowedURISchemes] = o.value
+}
+
+func (o *withAllowedURISchemes) SetHTMLOption(c *Config) {
+	c.AllowedURISchemes = append(c.AllowedURISchemes, o.value...)
+}
+
+// WithAllowedURISchemes is a functional option that extends the scheme
+// allowlist used by WithSafelink, on top of the defaults
+// (http, https, mailto, ftp, tel).
+func WithAllowedURISchemes(schemes ...string) interface {
+	renderer.Option
+	Option
+} {
+	return &withAllowedURISchemes{schemes}
+}
+
+// NofollowLinks is an option name used in WithNofollowLinks.
+const NofollowLinks renderer.OptionName = "NofollowLinks"
+
+type withNofollowLinks struct {
+}
+
+func (o *withNofollowLinks) SetConfig(c *renderer.Config) {
+	c.Options[NofollowLinks] = true
+}
+
+func (o *withNofollowLinks) SetHTMLOption(c *Config) {
+	c.NofollowLinks = true
+}
+
+// WithNofollowLinks is a functional option that adds rel="nofollow" to
+// every rendered link.
+func WithNofollowLinks() interface {
+	renderer.Option
+	Option
+} {
+	return &withNofollowLinks{}
+}
+
+// NoreferrerLinks is an option name used in WithNoreferrerLinks.
+const NoreferrerLinks renderer.OptionName = "NoreferrerLinks"
+
+type withNoreferrerLinks struct {
+}
+
+func (o *withNoreferrerLinks) SetConfig(c *renderer.Config) {
+	c.Options[NoreferrerLinks] = true
+}
+
+func (o *withNoreferrerLinks) SetHTMLOption(c *Config) {
+	c.NoreferrerLinks = true
+}
+
+// WithNoreferrerLinks is a functional option that adds rel="noreferrer" to
+// every rendered link.
+func WithNoreferrerLinks() interface {
+	renderer.Option
+	Option
+} {
+	return &withNoreferrerLinks{}
+}
+
+// NoopenerLinks is an option name used in WithNoopenerLinks.
+const NoopenerLinks renderer.OptionName = "NoopenerLinks"
+
+type withNoopenerLinks struct {
+}
+
+func (o *withNoopenerLinks) SetConfig(c *renderer.Config) {
+	c.Options[NoopenerLinks] = true
+}
+
+func (o *withNoopenerLinks) SetHTMLOption(c *Config) {
+	c.NoopenerLinks = true
+}
+
+// WithNoopenerLinks is a functional option that adds rel="noopener" to
+// every rendered link.
+func WithNoopenerLinks() interface {
+	renderer.Option
+	Option
+} {
+	return &withNoopenerLinks{}
+}
+
+// HrefTargetBlank is an option name used in WithHrefTargetBlank.
+const HrefTargetBlank renderer.OptionName = "HrefTargetBlank"
+
+type withHrefTargetBlank struct {
+}
+
+func (o *withHrefTargetBlank) SetConfig(c *renderer.Config) {
+	c.Options[HrefTargetBlank] = true
+}
+
+func (o *withHrefTargetBlank) SetHTMLOption(c *Config) {
+	c.HrefTargetBlank = true
+}
+
+// WithHrefTargetBlank is a functional option that adds target="_blank" to
+// every rendered link.
+func WithHrefTargetBlank() interface {
+	renderer.Option
+	Option
+} {
+	return &withHrefTargetBlank{}
+}
+
 // A Renderer struct is an implementation of renderer.NodeRenderer that renders
 // nodes as (X)HTML.
 type Renderer struct {
@@ -194,7 +444,21 @@ func (r *Renderer) writeLines(w util.BufWriter, source []byte, n ast.Node) {
 }
 
 func (r *Renderer) renderDocument(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
-	// nothing to do
+	if entering {
+		var toc []byte
+		if r.TOC {
+			toc = r.renderTOC(node, source)
+		}
+		if r.CompletePage != nil {
+			r.writeCompletePagePrologue(w, toc)
+		} else if toc != nil {
+			w.Write(toc)
+		}
+		return ast.WalkContinue, nil
+	}
+	if r.CompletePage != nil {
+		r.writeCompletePageEpilogue(w)
+	}
 	return ast.WalkContinue, nil
 }
 
@@ -271,6 +535,16 @@ func (r *Renderer) renderHTMLBlock(w util.BufWriter, source []byte, node ast.Nod
 				line := n.Lines().At(i)
 				w.Write(line.Value(source))
 			}
+		} else if r.Sanitizer != nil {
+			var buf bytes.Buffer
+			l := n.Lines().Len()
+			for i := 0; i < l; i++ {
+				line := n.Lines().At(i)
+				buf.Write(line.Value(source))
+			}
+			if err := r.Sanitizer.Sanitize(w, buf.Bytes()); err != nil {
+				return ast.WalkStop, err
+			}
 		} else {
 			w.WriteString("<!-- raw HTML omitted -->\n")
 		}
@@ -279,6 +553,11 @@ func (r *Renderer) renderHTMLBlock(w util.BufWriter, source []byte, node ast.Nod
 			if r.Unsafe {
 				closure := n.ClosureLine
 				w.Write(closure.Value(source))
+			} else if r.Sanitizer != nil {
+				closure := n.ClosureLine
+				if err := r.Sanitizer.Sanitize(w, closure.Value(source)); err != nil {
+					return ast.WalkStop, err
+				}
 			} else {
 				w.WriteString("<!-- raw HTML omitted -->\n")
 			}
@@ -365,8 +644,12 @@ func (r *Renderer) renderAutoLink(w util.BufWriter, source []byte, node ast.Node
 	if n.AutoLinkType == ast.AutoLinkEmail && !bytes.HasPrefix(bytes.ToLower(value), []byte("mailto:")) {
 		w.WriteString("mailto:")
 	}
-	w.Write(util.EscapeHTML(util.URLEscape(value, false)))
-	w.WriteString(`">`)
+	if r.isSafeURL(value) {
+		w.Write(util.EscapeHTML(util.URLEscape(value, false)))
+	}
+	w.WriteByte('"')
+	r.writeLinkRelAttrs(w)
+	w.WriteByte('>')
 	w.Write(util.EscapeHTML(value))
 	w.WriteString(`</a>`)
 	return ast.WalkContinue, nil
@@ -415,7 +698,7 @@ func (r *Renderer) renderLink(w util.BufWriter, source []byte, node ast.Node, en
 	n := node.(*ast.Link)
 	if entering {
 		w.WriteString("<a href=\"")
-		if r.Unsafe || !IsDangerousURL(n.Destination) {
+		if (r.Unsafe || !r.isDangerousURL(n.Destination)) && r.isSafeURL(n.Destination) {
 			w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
 		}
 		w.WriteByte('"')
@@ -424,6 +707,7 @@ func (r *Renderer) renderLink(w util.BufWriter, source []byte, node ast.Node, en
 			r.Writer.Write(w, n.Title)
 			w.WriteByte('"')
 		}
+		r.writeLinkRelAttrs(w)
 		w.WriteByte('>')
 	} else {
 		w.WriteString("</a>")
@@ -436,7 +720,7 @@ func (r *Renderer) renderImage(w util.BufWriter, source []byte, node ast.Node, e
 	}
 	n := node.(*ast.Image)
 	w.WriteString("<img src=\"")
-	if r.Unsafe || !IsDangerousURL(n.Destination) {
+	if r.Unsafe || !r.isDangerousURL(n.Destination) {
 		w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
 	}
 	w.WriteString(`" alt="`)
@@ -456,9 +740,24 @@ func (r *Renderer) renderImage(w util.BufWriter, source []byte, node ast.Node, e
 }
 
 func (r *Renderer) renderRawHTML(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
 	if r.Unsafe {
 		return ast.WalkContinue, nil
 	}
+	if r.Sanitizer != nil {
+		raw := n.(*ast.RawHTML)
+		var buf bytes.Buffer
+		for i := 0; i < raw.Segments.Len(); i++ {
+			segment := raw.Segments.At(i)
+			buf.Write(segment.Value(source))
+		}
+		if err := r.Sanitizer.Sanitize(w, buf.Bytes()); err != nil {
+			return ast.WalkStop, err
+		}
+		return ast.WalkSkipChildren, nil
+	}
 	w.WriteString("<!-- raw HTML omitted -->")
 	return ast.WalkSkipChildren, nil
 }
@@ -522,21 +821,39 @@ func escapeRune(writer util.BufWriter, r rune) {
 	writer.WriteRune(util.ToValidRune(r))
 }
 
+// escapeByteSet holds every byte for which util.EscapeHTMLByte returns a
+// replacement, computed once so RawWrite can locate the next byte needing
+// escaping with a single bytes.IndexAny call instead of probing
+// EscapeHTMLByte one byte at a time.
+var escapeByteSet string
+
+func init() {
+	var set []byte
+	for i := 0; i < 256; i++ {
+		if util.EscapeHTMLByte(byte(i)) != nil {
+			set = append(set, byte(i))
+		}
+	}
+	escapeByteSet = string(set)
+}
+
 func (d *defaultWriter) RawWrite(writer util.BufWriter, source []byte) {
 	n := 0
 	l := len(source)
-	for i := 0; i < l; i++ {
-		v := util.EscapeHTMLByte(source[i])
-		if v != nil {
-			writer.Write(source[i-n : i])
-			n = 0
-			writer.Write(v)
-			continue
+	for n < l {
+		idx := bytes.IndexAny(source[n:l], escapeByteSet)
+		if idx < 0 {
+			break
+		}
+		pos := n + idx
+		if pos > n {
+			writer.Write(source[n:pos])
 		}
-		n++
+		writer.Write(util.EscapeHTMLByte(source[pos]))
+		n = pos + 1
 	}
-	if n != 0 {
-		writer.Write(source[l-n:])
+	if n != l {
+		writer.Write(source[n:])
 	}
 }
 
@@ -546,6 +863,16 @@ func (d *defaultWriter) Write(writer util.BufWriter, source []byte) {
 	limit := len(source)
 	n := 0
 	for i := 0; i < limit; i++ {
+		if !escaped {
+			// Skip over long runs that contain neither an entity/escape
+			// trigger using a single, stdlib-optimized scan rather than
+			// inspecting each byte in Go.
+			idx := bytes.IndexAny(source[i:limit], "&\\")
+			if idx < 0 {
+				break
+			}
+			i += idx
+		}
 		c := source[i]
 		if escaped {
 			if util.IsPunct(c) {
@@ -623,6 +950,81 @@ var bVb = []byte("vbscript:")
 var bFile = []byte("file:")
 var bData = []byte("data:")
 
+// isDangerousURL reports whether destination should be dropped. It defers
+// to r.URLFilter when one is configured (see WithURLFilter), otherwise it
+// falls back to the package-level IsDangerousURL.
+func (r *Renderer) isDangerousURL(destination []byte) bool {
+	if r.URLFilter != nil {
+		return r.URLFilter.IsDangerousURL(destination)
+	}
+	return IsDangerousURL(destination)
+}
+
+// isSafeURL returns true if destination is allowed under the current
+// Safelink configuration. When Safelink is disabled, every destination is
+// considered safe.
+func (r *Renderer) isSafeURL(destination []byte) bool {
+	if !r.Safelink {
+		return true
+	}
+	scheme, ok := uriScheme(destination)
+	if !ok {
+		// no scheme (e.g. a relative path or fragment) is always allowed
+		return true
+	}
+	for _, s := range r.AllowedURISchemes {
+		if len(scheme) == len(s) && strings.EqualFold(util.BytesToReadOnlyString(scheme), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// uriScheme reports the scheme prefix of destination (the part before the
+// first ':'), following RFC 3986's scheme grammar.
+func uriScheme(destination []byte) ([]byte, bool) {
+	for i, c := range destination {
+		switch {
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			continue
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+			continue
+		case c == ':':
+			if i == 0 {
+				return nil, false
+			}
+			return destination[:i], true
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// writeLinkRelAttrs writes the rel="..." and target="_blank" attributes
+// configured via WithNofollowLinks, WithNoreferrerLinks, WithNoopenerLinks,
+// and WithHrefTargetBlank.
+func (r *Renderer) writeLinkRelAttrs(w util.BufWriter) {
+	var rels []string
+	if r.NofollowLinks {
+		rels = append(rels, "nofollow")
+	}
+	if r.NoreferrerLinks {
+		rels = append(rels, "noreferrer")
+	}
+	if r.NoopenerLinks {
+		rels = append(rels, "noopener")
+	}
+	if len(rels) > 0 {
+		w.WriteString(` rel="`)
+		w.WriteString(strings.Join(rels, " "))
+		w.WriteByte('"')
+	}
+	if r.HrefTargetBlank {
+		w.WriteString(` target="_blank"`)
+	}
+}
+
 // IsDangerousURL returns true if the given url seems a potentially dangerous url,
 // otherwise false.
 func IsDangerousURL(url []byte) bool {
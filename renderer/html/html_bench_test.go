@@ -0,0 +1,181 @@
+package html
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/yuin/goldmark/util"
+)
+
+func genProse(n int) []byte {
+	const words = "the quick brown fox jumps over the lazy dog & \"friends\" <em>very</em> fast\n"
+	var b bytes.Buffer
+	for b.Len() < n {
+		b.WriteString(words)
+	}
+	return b.Bytes()[:n]
+}
+
+func genCodeBlock(n int) []byte {
+	const line = "func main() { fmt.Println(\"<hello & goodbye>\") }\n"
+	var b bytes.Buffer
+	for b.Len() < n {
+		b.WriteString(line)
+	}
+	return b.Bytes()[:n]
+}
+
+func BenchmarkRawWriteProse(b *testing.B) {
+	source := genProse(1 << 16)
+	b.SetBytes(int64(len(source)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		DefaultWriter.RawWrite(&buf, source)
+	}
+}
+
+func BenchmarkRawWriteCode(b *testing.B) {
+	source := genCodeBlock(1 << 16)
+	b.SetBytes(int64(len(source)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		DefaultWriter.RawWrite(&buf, source)
+	}
+}
+
+func BenchmarkWriteProse(b *testing.B) {
+	source := genProse(1 << 16)
+	b.SetBytes(int64(len(source)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		DefaultWriter.Write(&buf, source)
+	}
+}
+
+// naiveRawWrite is the pre-optimization, byte-at-a-time reference
+// implementation that RawWrite is fuzz-tested against.
+func naiveRawWrite(source []byte) []byte {
+	var buf bytes.Buffer
+	n := 0
+	l := len(source)
+	for i := 0; i < l; i++ {
+		v := util.EscapeHTMLByte(source[i])
+		if v != nil {
+			buf.Write(source[i-n : i])
+			n = 0
+			buf.Write(v)
+			continue
+		}
+		n++
+	}
+	if n != 0 {
+		buf.Write(source[l-n:])
+	}
+	return buf.Bytes()
+}
+
+func FuzzRawWrite(f *testing.F) {
+	f.Add([]byte("plain text"))
+	f.Add([]byte("<tag> & \"quoted\" 'text'"))
+	f.Add(genCodeBlock(4096))
+	f.Fuzz(func(t *testing.T, source []byte) {
+		var buf bytes.Buffer
+		DefaultWriter.RawWrite(&buf, source)
+		want := naiveRawWrite(source)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatalf("RawWrite(%q) = %q, want %q", source, buf.Bytes(), want)
+		}
+	})
+}
+
+// naiveWrite is the pre-optimization reference implementation of Write: it
+// walks source one byte at a time instead of fast-skipping to the next '&'
+// or '\\' with bytes.IndexAny, but is otherwise the same entity/backslash
+// escape state machine that Write is fuzz-tested against.
+func naiveWrite(source []byte) []byte {
+	var buf bytes.Buffer
+	escaped := false
+	ok := false
+	limit := len(source)
+	n := 0
+	for i := 0; i < limit; i++ {
+		c := source[i]
+		if escaped {
+			if util.IsPunct(c) {
+				DefaultWriter.RawWrite(&buf, source[n:i-1])
+				n = i
+				escaped = false
+				continue
+			}
+		}
+		if c == '&' {
+			pos := i
+			next := i + 1
+			if next < limit && source[next] == '#' {
+				nnext := next + 1
+				nc := source[nnext]
+				if nnext < limit && nc == 'x' || nc == 'X' {
+					start := nnext + 1
+					i, ok = util.ReadWhile(source, [2]int{start, limit}, util.IsHexDecimal)
+					if ok && i < limit && source[i] == ';' {
+						v, _ := strconv.ParseUint(util.BytesToReadOnlyString(source[start:i]), 16, 32)
+						DefaultWriter.RawWrite(&buf, source[n:pos])
+						n = i + 1
+						escapeRune(&buf, rune(v))
+						continue
+					}
+				} else if nc >= '0' && nc <= '9' {
+					start := nnext
+					i, ok = util.ReadWhile(source, [2]int{start, limit}, util.IsNumeric)
+					if ok && i < limit && i-start < 8 && source[i] == ';' {
+						v, _ := strconv.ParseUint(util.BytesToReadOnlyString(source[start:i]), 0, 32)
+						DefaultWriter.RawWrite(&buf, source[n:pos])
+						n = i + 1
+						escapeRune(&buf, rune(v))
+						continue
+					}
+				}
+			} else {
+				start := next
+				i, ok = util.ReadWhile(source, [2]int{start, limit}, util.IsAlphaNumeric)
+				if ok && i < limit && source[i] == ';' {
+					name := util.BytesToReadOnlyString(source[start:i])
+					entity, ok := util.LookUpHTML5EntityByName(name)
+					if ok {
+						DefaultWriter.RawWrite(&buf, source[n:pos])
+						n = i + 1
+						DefaultWriter.RawWrite(&buf, entity.Characters)
+						continue
+					}
+				}
+			}
+			i = next - 1
+		}
+		if c == '\\' {
+			escaped = true
+			continue
+		}
+		escaped = false
+	}
+	DefaultWriter.RawWrite(&buf, source[n:len(source)])
+	return buf.Bytes()
+}
+
+func FuzzWrite(f *testing.F) {
+	f.Add([]byte("plain text"))
+	f.Add([]byte("<tag> & \"quoted\" 'text'"))
+	f.Add([]byte("&amp; &#65; &#x41; &nosuchentity; \\* \\a"))
+	f.Add(genProse(4096))
+	f.Fuzz(func(t *testing.T, source []byte) {
+		var buf bytes.Buffer
+		DefaultWriter.Write(&buf, source)
+		want := naiveWrite(source)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatalf("Write(%q) = %q, want %q", source, buf.Bytes(), want)
+		}
+	})
+}
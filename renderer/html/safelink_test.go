@@ -0,0 +1,75 @@
+package html
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+func TestSafelinkDropsDisallowedScheme(t *testing.T) {
+	r := NewRenderer(WithSafelink()).(*Renderer)
+	n := ast.NewLink()
+	n.Destination = []byte("javascript:alert(1)")
+	var buf bytes.Buffer
+	if _, err := r.renderLink(&buf, nil, n, true); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("javascript:")) {
+		t.Fatalf("renderLink with Safelink wrote a disallowed scheme: %q", buf.String())
+	}
+}
+
+func TestSafelinkAllowsAllowlistedScheme(t *testing.T) {
+	r := NewRenderer(WithSafelink()).(*Renderer)
+	n := ast.NewLink()
+	n.Destination = []byte("https://example.com")
+	var buf bytes.Buffer
+	if _, err := r.renderLink(&buf, nil, n, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`href="https://example.com"`)) {
+		t.Fatalf("renderLink with Safelink dropped an allowed scheme: %q", buf.String())
+	}
+}
+
+func TestSafelinkCustomAllowedSchemes(t *testing.T) {
+	r := NewRenderer(WithSafelink(), WithAllowedURISchemes("myapp")).(*Renderer)
+	n := ast.NewLink()
+	n.Destination = []byte("myapp://open")
+	var buf bytes.Buffer
+	if _, err := r.renderLink(&buf, nil, n, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`href="myapp://open"`)) {
+		t.Fatalf("renderLink did not allow a scheme added via WithAllowedURISchemes: %q", buf.String())
+	}
+}
+
+func TestRenderLinkRelAndTargetAttrs(t *testing.T) {
+	r := NewRenderer(WithNofollowLinks(), WithNoreferrerLinks(), WithNoopenerLinks(), WithHrefTargetBlank()).(*Renderer)
+	n := ast.NewLink()
+	n.Destination = []byte("https://example.com")
+	var buf bytes.Buffer
+	if _, err := r.renderLink(&buf, nil, n, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`rel="nofollow noreferrer noopener"`, `target="_blank"`} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Fatalf("renderLink output %q missing %q", buf.String(), want)
+		}
+	}
+}
+
+func TestSafelinkWithoutOptionAllowsAnyScheme(t *testing.T) {
+	r := NewRenderer().(*Renderer)
+	n := ast.NewLink()
+	n.Destination = []byte("irc://example.org")
+	var buf bytes.Buffer
+	if _, err := r.renderLink(&buf, nil, n, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`href="irc://example.org"`)) {
+		t.Fatalf("renderLink without Safelink should leave the destination alone, got %q", buf.String())
+	}
+}
@@ -0,0 +1,76 @@
+package html
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// upperSanitizer is a trivial Sanitizer used to prove that raw HTML is
+// actually routed through the configured Sanitizer, rather than just
+// checking that it isn't dropped.
+type upperSanitizer struct{}
+
+func (upperSanitizer) Sanitize(dst io.Writer, html []byte) error {
+	_, err := dst.Write(bytes.ToUpper(html))
+	return err
+}
+
+func TestSanitizerRewritesHTMLBlock(t *testing.T) {
+	source := []byte("<div>hi</div>\n")
+	r := NewRenderer(WithSanitizer(upperSanitizer{})).(*Renderer)
+	n := ast.NewHTMLBlock(ast.HTMLBlockType1)
+	n.Lines().Append(text.NewSegment(0, len(source)))
+	var buf bytes.Buffer
+	if _, err := r.renderHTMLBlock(&buf, source, n, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<DIV>HI</DIV>\n"; buf.String() != want {
+		t.Fatalf("renderHTMLBlock with Sanitizer = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSanitizerRewritesRawHTML(t *testing.T) {
+	source := []byte("<br>")
+	r := NewRenderer(WithSanitizer(upperSanitizer{})).(*Renderer)
+	n := ast.NewRawHTML()
+	n.Segments.Append(text.NewSegment(0, len(source)))
+	var buf bytes.Buffer
+	if _, err := r.renderRawHTML(&buf, source, n, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<BR>"; buf.String() != want {
+		t.Fatalf("renderRawHTML with Sanitizer = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNoSanitizerOmitsRawHTML(t *testing.T) {
+	source := []byte("<script>evil()</script>")
+	r := NewRenderer().(*Renderer)
+	n := ast.NewRawHTML()
+	n.Segments.Append(text.NewSegment(0, len(source)))
+	var buf bytes.Buffer
+	if _, err := r.renderRawHTML(&buf, source, n, true); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("script")) {
+		t.Fatalf("renderRawHTML without Sanitizer leaked raw HTML: %q", buf.String())
+	}
+}
+
+func TestUnsafeBypassesSanitizer(t *testing.T) {
+	source := []byte("<script>evil()</script>")
+	r := NewRenderer(WithUnsafe(), WithSanitizer(upperSanitizer{})).(*Renderer)
+	n := ast.NewRawHTML()
+	n.Segments.Append(text.NewSegment(0, len(source)))
+	var buf bytes.Buffer
+	if _, err := r.renderRawHTML(&buf, source, n, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := string(source); buf.String() != want {
+		t.Fatalf("renderRawHTML with Unsafe = %q, want verbatim %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,318 @@
+package html
+
+import (
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// A SmartyOption interface configures a SmartyWriter.
+type SmartyOption interface {
+	SetSmartyOption(*smartyConfig)
+}
+
+type smartyConfig struct {
+	Fractions    bool
+	Dashes       bool
+	LatexDashes  bool
+	AngledQuotes bool
+	QuotesNBSP   bool
+}
+
+type withFractions struct{}
+
+func (o *withFractions) SetSmartyOption(c *smartyConfig) { c.Fractions = true }
+
+// WithFractions is a SmartyOption that converts simple fractions like "1/2"
+// into their HTML entity form (e.g. "&frac12;").
+func WithFractions() SmartyOption { return &withFractions{} }
+
+type withDashes struct{}
+
+func (o *withDashes) SetSmartyOption(c *smartyConfig) { c.Dashes = true }
+
+// WithDashes is a SmartyOption that converts "--" and "---" into en and em
+// dashes.
+func WithDashes() SmartyOption { return &withDashes{} }
+
+type withLatexDashes struct{}
+
+func (o *withLatexDashes) SetSmartyOption(c *smartyConfig) {
+	c.Dashes = true
+	c.LatexDashes = true
+}
+
+// WithLatexDashes is a SmartyOption like WithDashes, but swaps which of
+// "--"/"---" maps to the en dash and which maps to the em dash, matching
+// the convention used by LaTeX.
+func WithLatexDashes() SmartyOption { return &withLatexDashes{} }
+
+type withAngledQuotes struct{}
+
+func (o *withAngledQuotes) SetSmartyOption(c *smartyConfig) { c.AngledQuotes = true }
+
+// WithAngledQuotes is a SmartyOption that renders double quotes as French
+// guillemets ("&laquo;"/"&raquo;") instead of curly quotes.
+func WithAngledQuotes() SmartyOption { return &withAngledQuotes{} }
+
+type withQuotesNBSP struct{}
+
+func (o *withQuotesNBSP) SetSmartyOption(c *smartyConfig) { c.QuotesNBSP = true }
+
+// WithQuotesNBSP is a SmartyOption that inserts a non-breaking space
+// between angled quotes (see WithAngledQuotes) and the text they enclose.
+func WithQuotesNBSP() SmartyOption { return &withQuotesNBSP{} }
+
+// A SmartyWriter is a Writer that decorates another Writer with
+// SmartyPants-style typographic substitution: straight quotes become curly
+// quotes, "..." becomes an ellipsis, and "(c)"/"(r)"/"(tm)" become their
+// HTML entities. "--"/"---" become dashes only with WithDashes or
+// WithLatexDashes.
+type SmartyWriter struct {
+	Writer
+	smartyConfig
+}
+
+// NewSmartyWriter returns a new SmartyWriter that wraps w, applying the
+// given SmartyOptions.
+func NewSmartyWriter(w Writer, opts ...SmartyOption) *SmartyWriter {
+	s := &SmartyWriter{Writer: w}
+	for _, opt := range opts {
+		opt.SetSmartyOption(&s.smartyConfig)
+	}
+	return s
+}
+
+type withSmartyPants struct {
+	opts []SmartyOption
+}
+
+func (o *withSmartyPants) SetConfig(c *renderer.Config) {
+	if w, ok := c.Options[TextWriter].(Writer); ok {
+		c.Options[TextWriter] = NewSmartyWriter(w, o.opts...)
+		return
+	}
+	c.Options[TextWriter] = NewSmartyWriter(DefaultWriter, o.opts...)
+}
+
+func (o *withSmartyPants) SetHTMLOption(c *Config) {
+	c.Writer = NewSmartyWriter(c.Writer, o.opts...)
+}
+
+// WithSmartyPants is a functional option that wraps the renderer's Writer
+// with a SmartyWriter, applying the given SmartyOptions.
+func WithSmartyPants(opts ...SmartyOption) interface {
+	renderer.Option
+	Option
+} {
+	return &withSmartyPants{opts}
+}
+
+// Write implements Writer.Write. It runs the SmartyPants substitutions over
+// source and hands the result to the wrapped Writer, so backslash-escape
+// and entity-reference handling still happen exactly once, in the wrapped
+// Writer.
+func (s *SmartyWriter) Write(writer util.BufWriter, source []byte) {
+	s.process(writer, source, false)
+}
+
+// RawWrite implements Writer.RawWrite. Unlike Write, raw segments (code
+// spans, code blocks, etc.) are never given typographic treatment; it is
+// forwarded untouched.
+func (s *SmartyWriter) RawWrite(writer util.BufWriter, source []byte) {
+	s.Writer.RawWrite(writer, source)
+}
+
+// process scans source for SmartyPants triggers, flushing the plain runs in
+// between through the wrapped Writer and writing the HTML entities for
+// recognized sequences directly to writer, so the entities' own "&" is
+// never re-escaped or re-decoded by the wrapped Writer.
+func (s *SmartyWriter) process(writer util.BufWriter, source []byte, inRaw bool) {
+	n := len(source)
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			s.Writer.Write(writer, source[start:end])
+		}
+	}
+	i := 0
+	for i < n {
+		c := source[i]
+		if c == '\\' && i+1 < n && util.IsPunct(source[i+1]) {
+			// a backslash-escaped character stays in the plain run, for
+			// the wrapped Writer to unescape
+			i += 2
+			continue
+		}
+		switch c {
+		case '-':
+			if s.Dashes {
+				if i+2 < n && source[i+1] == '-' && source[i+2] == '-' {
+					flush(i)
+					s.writeDash(writer, 3)
+					i += 3
+					start = i
+					continue
+				}
+				if i+1 < n && source[i+1] == '-' {
+					flush(i)
+					s.writeDash(writer, 2)
+					i += 2
+					start = i
+					continue
+				}
+			}
+		case '.':
+			if i+2 < n && source[i+1] == '.' && source[i+2] == '.' {
+				flush(i)
+				writer.Write([]byte("&hellip;"))
+				i += 3
+				start = i
+				continue
+			}
+		case '(':
+			if entity, adv, ok := matchParenEntity(source[i:]); ok {
+				flush(i)
+				writer.Write(entity)
+				i += adv
+				start = i
+				continue
+			}
+		case '"':
+			flush(i)
+			s.writeDoubleQuote(writer, source, i)
+			i++
+			start = i
+			continue
+		case '\'':
+			flush(i)
+			s.writeSingleQuote(writer, source, i)
+			i++
+			start = i
+			continue
+		default:
+			// The preceding-digit check keeps "11/2" from matching "1/2"
+			// partway through the "11"; matchFraction itself guards the
+			// trailing side (e.g. "1/23").
+			if s.Fractions && c >= '1' && c <= '9' && !(i > 0 && source[i-1] >= '0' && source[i-1] <= '9') {
+				if entity, adv, ok := matchFraction(source[i:]); ok {
+					flush(i)
+					writer.Write(entity)
+					i += adv
+					start = i
+					continue
+				}
+			}
+		}
+		i++
+	}
+	flush(n)
+}
+
+func (s *SmartyWriter) writeDash(writer util.BufWriter, run int) {
+	em := run == 3
+	if s.LatexDashes {
+		em = !em
+	}
+	if em {
+		writer.Write([]byte("&mdash;"))
+	} else {
+		writer.Write([]byte("&ndash;"))
+	}
+}
+
+func (s *SmartyWriter) writeDoubleQuote(writer util.BufWriter, source []byte, i int) {
+	opening := isOpeningQuotePos(source, i)
+	if s.AngledQuotes {
+		if s.QuotesNBSP {
+			if opening {
+				writer.Write([]byte("&laquo;&nbsp;"))
+			} else {
+				writer.Write([]byte("&nbsp;&raquo;"))
+			}
+			return
+		}
+		if opening {
+			writer.Write([]byte("&laquo;"))
+		} else {
+			writer.Write([]byte("&raquo;"))
+		}
+		return
+	}
+	if opening {
+		writer.Write([]byte("&ldquo;"))
+	} else {
+		writer.Write([]byte("&rdquo;"))
+	}
+}
+
+func (s *SmartyWriter) writeSingleQuote(writer util.BufWriter, source []byte, i int) {
+	if isOpeningQuotePos(source, i) {
+		writer.Write([]byte("&lsquo;"))
+		return
+	}
+	writer.Write([]byte("&rsquo;"))
+}
+
+// isOpeningQuotePos decides whether the quote at source[i] opens or closes
+// a quotation, based on the surrounding whitespace/punctuation context.
+func isOpeningQuotePos(source []byte, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := source[i-1]
+	return prev == ' ' || prev == '\t' || prev == '\n' ||
+		prev == '(' || prev == '[' || prev == '{' ||
+		util.IsPunct(prev)
+}
+
+var parenEntities = []struct {
+	match  string
+	entity []byte
+}{
+	{"(c)", []byte("&copy;")},
+	{"(C)", []byte("&copy;")},
+	{"(r)", []byte("&reg;")},
+	{"(R)", []byte("&reg;")},
+	{"(tm)", []byte("&trade;")},
+	{"(TM)", []byte("&trade;")},
+}
+
+func matchParenEntity(source []byte) ([]byte, int, bool) {
+	for _, pe := range parenEntities {
+		if len(source) >= len(pe.match) && string(source[:len(pe.match)]) == pe.match {
+			return pe.entity, len(pe.match), true
+		}
+	}
+	return nil, 0, false
+}
+
+// fractionEntities maps simple fractions to their HTML output. "1/2", "1/4"
+// and "3/4" have dedicated HTML5 named character references (frac12/frac14/
+// frac34); the rest have no named entity, so they use numeric character
+// references for the corresponding Unicode vulgar fraction glyphs instead
+// of inventing entity names that browsers won't recognize.
+var fractionEntities = map[string][]byte{
+	"1/2": []byte("&frac12;"),
+	"1/3": []byte("&#8531;"), // ⅓
+	"2/3": []byte("&#8532;"), // ⅔
+	"1/4": []byte("&frac14;"),
+	"3/4": []byte("&frac34;"),
+	"1/8": []byte("&#8539;"), // ⅛
+	"3/8": []byte("&#8540;"), // ⅜
+	"5/8": []byte("&#8541;"), // ⅝
+	"7/8": []byte("&#8542;"), // ⅞
+}
+
+func matchFraction(source []byte) ([]byte, int, bool) {
+	if len(source) < 3 {
+		return nil, 0, false
+	}
+	if entity, ok := fractionEntities[string(source[:3])]; ok {
+		// avoid matching the start of a longer number like "1/23"
+		if len(source) > 3 && source[3] >= '0' && source[3] <= '9' {
+			return nil, 0, false
+		}
+		return entity, 3, true
+	}
+	return nil, 0, false
+}